@@ -0,0 +1,62 @@
+package uow
+
+import (
+	"context"
+	"errors"
+)
+
+// IsolationLevel mirrors sql.IsolationLevel but is backend-neutral so the
+// same Option works against database/sql, pgx and other TxBeginner
+// adapters. Ordinal values line up with database/sql's own constants, so
+// the sql adapter can convert with a plain cast.
+type IsolationLevel int
+
+const (
+	LevelDefault IsolationLevel = iota
+	LevelReadUncommitted
+	LevelReadCommitted
+	LevelWriteCommitted
+	LevelRepeatableRead
+	LevelSnapshot
+	LevelSerializable
+	LevelLinearizable
+)
+
+// TxOptions configures how a transaction is started, independent of which
+// backend TxBeginner ends up opening it.
+type TxOptions struct {
+	Isolation IsolationLevel
+	ReadOnly  bool
+}
+
+// Tx is the minimal transaction contract Uow needs from any backend: a way
+// to finalize it, and a way for repositories to get at the driver-native
+// handle (*sql.Tx, pgx.Tx, mongo.SessionContext, ...) via Handle.
+type Tx interface {
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+	Handle() any
+}
+
+// TxBeginner starts a new Tx against a backend. Adapters exist for
+// database/sql (sql_backend.go), pgx (pgx_backend.go) and MongoDB
+// (mongo_backend.go); callers can also implement it directly to plug in an
+// in-memory fake for tests.
+type TxBeginner interface {
+	Begin(ctx context.Context, opts TxOptions) (Tx, error)
+}
+
+// Savepointer is implemented by backends whose Tx supports nested
+// transactions via savepoints (database/sql, pgx). Backends that don't
+// (MongoDB) leave DoTx's nested path unavailable; DoTx then returns
+// ErrNestedTxUnsupported instead of attempting a savepoint.
+type Savepointer interface {
+	Savepoint(ctx context.Context, name string) error
+	RollbackToSavepoint(ctx context.Context, name string) error
+	ReleaseSavepoint(ctx context.Context, name string) error
+}
+
+// ErrNestedTxUnsupported is returned by DoTx when it is entered with a ctx
+// that already carries a transaction whose backend does not implement
+// Savepointer.
+var ErrNestedTxUnsupported = errors.New("uow: backend does not support nested transactions")
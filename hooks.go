@@ -0,0 +1,83 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CommitHook and RollbackHook are callbacks queued via OnCommit/OnRollback.
+// They run after the underlying transaction has been durably committed or
+// rolled back, making them a clean place to publish domain events, enqueue
+// jobs, or invalidate caches without leaking that concern into repository
+// code (the transactional-outbox pattern).
+type CommitHook func(ctx context.Context) error
+type RollbackHook func(ctx context.Context) error
+
+// OnCommit queues fn to run, in FIFO order alongside any other hooks
+// registered during this Do/DoTx call, once the ctx-bound transaction
+// commits successfully. Hooks registered on a transaction that ends up
+// rolling back are dropped without running.
+func (u *Uow) OnCommit(ctx context.Context, fn CommitHook) error {
+	h, ok := txHandleFromContext(ctx)
+	if !ok {
+		return errors.New("no transaction bound to context")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.commitHooks = append(h.commitHooks, fn)
+	return nil
+}
+
+// OnRollback queues fn to run, in FIFO order alongside any other hooks
+// registered during this Do/DoTx call, once the ctx-bound transaction
+// rolls back successfully, or once its Commit fails (CommitOrRollback then
+// settles it as rolled back). Hooks registered on a transaction that ends
+// up committing are dropped without running.
+func (u *Uow) OnRollback(ctx context.Context, fn RollbackHook) error {
+	h, ok := txHandleFromContext(ctx)
+	if !ok {
+		return errors.New("no transaction bound to context")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.rollbackHooks = append(h.rollbackHooks, fn)
+	return nil
+}
+
+// ClearHooks drops every hook queued so far on the ctx-bound transaction.
+// Each new Do/DoTx scope starts with empty hook lists of its own, so
+// callers only need it to discard hooks mid-scope.
+func (u *Uow) ClearHooks(ctx context.Context) error {
+	h, ok := txHandleFromContext(ctx)
+	if !ok {
+		return errors.New("no transaction bound to context")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.commitHooks = nil
+	h.rollbackHooks = nil
+	return nil
+}
+
+// runHooks runs each hook in order, recovering individual panics so one
+// misbehaving hook can't take down the others, and returns the first
+// error (panic or otherwise) encountered.
+func runHooks[H ~func(ctx context.Context) error](ctx context.Context, hooks []H) error {
+	var firstErr error
+	for _, hook := range hooks {
+		if err := runHookSafely(ctx, hook); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func runHookSafely[H ~func(ctx context.Context) error](ctx context.Context, hook H) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("uow: hook panicked: %v", r)
+		}
+	}()
+	return hook(ctx)
+}
@@ -0,0 +1,68 @@
+package uow
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoBeginner adapts a *mongo.Client to TxBeginner using a session per
+// transaction. It drives the session directly (StartTransaction /
+// CommitTransaction / AbortTransaction) rather than session.WithTransaction,
+// since Uow's Begin-then-later-Commit-or-Rollback contract doesn't fit
+// WithTransaction's own commit/retry loop; callers that want
+// WithTransaction's automatic retry-on-transient-error behavior should wrap
+// their own call to Do/DoTx in a retry loop instead.
+type mongoBeginner struct {
+	client *mongo.Client
+}
+
+// NewMongoBeginner wraps client so it can be passed to NewUowWithBeginner.
+func NewMongoBeginner(client *mongo.Client) TxBeginner {
+	return &mongoBeginner{client: client}
+}
+
+func (b *mongoBeginner) Begin(ctx context.Context, opts TxOptions) (Tx, error) {
+	session, err := b.client.StartSession()
+	if err != nil {
+		return nil, err
+	}
+	sessCtx := mongo.NewSessionContext(ctx, session)
+	if err := session.StartTransaction(mongoTxOptions(opts)); err != nil {
+		session.EndSession(ctx)
+		return nil, err
+	}
+	return &mongoTx{session: session, sessCtx: sessCtx}, nil
+}
+
+// mongoTx adapts a mongo.Session to Tx. It does not implement Savepointer:
+// MongoDB has no nested-transaction primitive, so a Do/DoTx call entered
+// with a Mongo transaction already on the context returns
+// ErrNestedTxUnsupported.
+type mongoTx struct {
+	session mongo.Session
+	sessCtx mongo.SessionContext
+}
+
+func (t *mongoTx) Commit(ctx context.Context) error {
+	defer t.session.EndSession(ctx)
+	return t.session.CommitTransaction(t.sessCtx)
+}
+
+func (t *mongoTx) Rollback(ctx context.Context) error {
+	defer t.session.EndSession(ctx)
+	return t.session.AbortTransaction(t.sessCtx)
+}
+
+func (t *mongoTx) Handle() any { return t.sessCtx }
+
+// mongoTxOptions builds the session TransactionOptions for opts. ReadOnly is
+// intentionally a no-op here: MongoDB multi-document transactions only run
+// against the primary, so setting a secondary read preference would make
+// every read inside the transaction fail with "read preference in a
+// transaction must be primary". Callers that need read scaling should issue
+// their reads outside a transaction instead.
+func mongoTxOptions(opts TxOptions) *options.TransactionOptions {
+	return options.Transaction()
+}
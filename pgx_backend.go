@@ -0,0 +1,84 @@
+package uow
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// pgxConnector is satisfied by both *pgx.Conn and *pgxpool.Pool, so
+// NewPgxBeginner works against a single connection or a pool.
+type pgxConnector interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// pgxBeginner adapts a pgxConnector to TxBeginner.
+type pgxBeginner struct {
+	conn pgxConnector
+}
+
+// NewPgxBeginner wraps conn (a *pgx.Conn or *pgxpool.Pool) so it can be
+// passed to NewUowWithBeginner.
+func NewPgxBeginner(conn pgxConnector) TxBeginner {
+	return &pgxBeginner{conn: conn}
+}
+
+func (b *pgxBeginner) Begin(ctx context.Context, opts TxOptions) (Tx, error) {
+	tx, err := b.conn.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel:   pgxIsoLevel(opts.Isolation),
+		AccessMode: pgxAccessMode(opts.ReadOnly),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pgxTx{tx: tx}, nil
+}
+
+// pgxTx adapts a pgx.Tx to Tx and Savepointer. Postgres supports savepoints
+// over the same wire protocol regardless of whether the outer transaction
+// came from a *pgx.Conn or a pooled connection, so nested Do/DoTx works
+// the same way it does against database/sql.
+type pgxTx struct {
+	tx pgx.Tx
+}
+
+func (t *pgxTx) Commit(ctx context.Context) error   { return t.tx.Commit(ctx) }
+func (t *pgxTx) Rollback(ctx context.Context) error { return t.tx.Rollback(ctx) }
+func (t *pgxTx) Handle() any                        { return t.tx }
+
+func (t *pgxTx) Savepoint(ctx context.Context, name string) error {
+	_, err := t.tx.Exec(ctx, "SAVEPOINT "+name)
+	return err
+}
+
+func (t *pgxTx) RollbackToSavepoint(ctx context.Context, name string) error {
+	_, err := t.tx.Exec(ctx, "ROLLBACK TO SAVEPOINT "+name)
+	return err
+}
+
+func (t *pgxTx) ReleaseSavepoint(ctx context.Context, name string) error {
+	_, err := t.tx.Exec(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+func pgxIsoLevel(level IsolationLevel) pgx.TxIsoLevel {
+	switch level {
+	case LevelReadUncommitted:
+		return pgx.ReadUncommitted
+	case LevelReadCommitted:
+		return pgx.ReadCommitted
+	case LevelRepeatableRead, LevelSnapshot:
+		return pgx.RepeatableRead
+	case LevelSerializable, LevelLinearizable:
+		return pgx.Serializable
+	default:
+		return ""
+	}
+}
+
+func pgxAccessMode(readOnly bool) pgx.TxAccessMode {
+	if readOnly {
+		return pgx.ReadOnly
+	}
+	return pgx.ReadWrite
+}
@@ -0,0 +1,52 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+)
+
+// sqlBeginner adapts a *sql.DB to TxBeginner.
+type sqlBeginner struct {
+	db *sql.DB
+}
+
+// NewSQLBeginner wraps db so it can be passed to NewUowWithBeginner. Most
+// callers can use NewUow(ctx, db) instead, which does this for them.
+func NewSQLBeginner(db *sql.DB) TxBeginner {
+	return &sqlBeginner{db: db}
+}
+
+func (b *sqlBeginner) Begin(ctx context.Context, opts TxOptions) (Tx, error) {
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.IsolationLevel(opts.Isolation),
+		ReadOnly:  opts.ReadOnly,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{tx: tx}, nil
+}
+
+// sqlTx adapts a *sql.Tx to Tx and Savepointer.
+type sqlTx struct {
+	tx *sql.Tx
+}
+
+func (t *sqlTx) Commit(ctx context.Context) error   { return t.tx.Commit() }
+func (t *sqlTx) Rollback(ctx context.Context) error { return t.tx.Rollback() }
+func (t *sqlTx) Handle() any                        { return t.tx }
+
+func (t *sqlTx) Savepoint(ctx context.Context, name string) error {
+	_, err := t.tx.ExecContext(ctx, "SAVEPOINT "+name)
+	return err
+}
+
+func (t *sqlTx) RollbackToSavepoint(ctx context.Context, name string) error {
+	_, err := t.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+	return err
+}
+
+func (t *sqlTx) ReleaseSavepoint(ctx context.Context, name string) error {
+	_, err := t.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
@@ -8,101 +8,428 @@ import (
 	"sync"
 )
 
+// UowInterface describes the unit-of-work contract. Every method that
+// touches a transaction threads context.Context through explicitly, since
+// the active Tx (if any) lives on the context rather than on the Uow
+// struct.
 type UowInterface interface {
 	Register(string, RepositoryFactory)
-	GetRepository(context.Context, string) (interface{}, error)
-	Do(context.Context, func(uow *Uow) error) error
-	CommitOrRollback(error) error
-	Rollback(error) error
+	GetRepository(ctx context.Context, name string) (interface{}, error)
+	Do(ctx context.Context, fn func(ctx context.Context, uow *Uow) error) error
+	DoTx(ctx context.Context, fn func(ctx context.Context, uow *Uow) error, opts ...Option) error
+	CommitOrRollback(ctx context.Context, res error) error
+	Rollback(ctx context.Context, res error) error
 	UnRegister(string)
 }
 
-var current *Uow
-
-type RepositoryFactory func(tx *sql.Tx) interface{}
+// RepositoryFactory builds a repository bound to handle, which is the
+// driver-native handle for the active backend: a ctx-scoped Tx's Handle()
+// (e.g. *sql.Tx, pgx.Tx, mongo.SessionContext) when Do has been entered,
+// or the Uow's plain handle (e.g. *sql.DB, *pgxpool.Pool, *mongo.Client)
+// otherwise. Repositories type-assert handle to whatever their backend
+// requires.
+type RepositoryFactory func(ctx context.Context, handle any) interface{}
 
+// Uow coordinates transaction lifecycle and repository lookup against any
+// backend reachable through a TxBeginner. A Uow is safe to reuse across
+// goroutines: nothing about an in-flight transaction is stored on the
+// struct itself, so concurrent calls to Do/DoTx each get their own
+// ctx-scoped transaction. The Repositories map is not guarded by a lock:
+// Register and UnRegister are not safe to call concurrently with each
+// other or with GetRepository.
 type Uow struct {
-	Db           *sql.DB
-	Tx           *sql.Tx
-	mu           sync.Mutex
+	beginner     TxBeginner
+	handle       any
 	Repositories map[string]RepositoryFactory
 }
 
-func Current() *Uow {
-	return current
-}
+// txCtxKey is the private key type used to stash the active transaction
+// handle on a context.Context.
+type txCtxKey struct{}
 
+// NewUow builds a Uow backed directly by database/sql, preserving the
+// original constructor for callers that don't need another backend. handle
+// is db itself, used by GetRepository whenever no transaction is active.
 func NewUow(ctx context.Context, db *sql.DB) (*Uow, error) {
-	current = &Uow{
+	return NewUowWithBeginner(ctx, NewSQLBeginner(db), db)
+}
+
+// NewUowWithBeginner builds a Uow against any TxBeginner (database/sql,
+// pgx, MongoDB, an in-memory fake for tests, ...). handle is passed to
+// RepositoryFactory by GetRepository whenever no transaction is active;
+// it is typically the same driver-native pool/client that beginner wraps.
+func NewUowWithBeginner(ctx context.Context, beginner TxBeginner, handle any) (*Uow, error) {
+	return &Uow{
+		beginner:     beginner,
+		handle:       handle,
 		Repositories: make(map[string]RepositoryFactory),
-		Db:           db,
-	}
-	return current, nil
+	}, nil
 }
 
 func (u *Uow) Register(name string, fc RepositoryFactory) {
 	u.Repositories[name] = fc
 }
 
+func (u *Uow) UnRegister(name string) {
+	delete(u.Repositories, name)
+}
+
+// GetRepository looks up the factory registered under name and invokes it
+// with the ctx-bound transaction's driver-native handle when Do/DoTx has
+// been entered, or with the Uow's plain handle otherwise.
 func (u *Uow) GetRepository(ctx context.Context, name string) (interface{}, error) {
-	if u.Tx == nil {
-		tx, err := u.Db.BeginTx(ctx, nil)
-		if err != nil {
-			return nil, err
-		}
-		u.Tx = tx
+	fc, ok := u.Repositories[name]
+	if !ok {
+		return nil, fmt.Errorf("repository %q is not registered", name)
+	}
+	if tx, ok := WithTx(ctx); ok {
+		return fc(ctx, tx.Handle()), nil
+	}
+	return fc(ctx, u.handle), nil
+}
+
+// RegisterTyped registers a repository factory that returns a concrete
+// type T instead of interface{}, for callers that would rather avoid the
+// type assertion at the GetRepository call site.
+func RegisterTyped[T any](u *Uow, name string, fc func(ctx context.Context, handle any) T) {
+	u.Register(name, func(ctx context.Context, handle any) interface{} {
+		return fc(ctx, handle)
+	})
+}
+
+// GetRepositoryTyped looks up name on u and type-asserts the result to T.
+func GetRepositoryTyped[T any](ctx context.Context, u *Uow, name string) (T, error) {
+	var zero T
+	repo, err := u.GetRepository(ctx, name)
+	if err != nil {
+		return zero, err
+	}
+	typed, ok := repo.(T)
+	if !ok {
+		return zero, fmt.Errorf("repository %q is not of type %T", name, zero)
 	}
-	repo := u.Repositories[name](u.Tx)
-	return repo, nil
+	return typed, nil
 }
 
-func (u *Uow) Do(ctx context.Context, fn func(uow *Uow) error) error {
-	u.mu.Lock()
-	if u.Tx != nil {
-		return errors.New("transaction already started")
+// WithTx returns the Tx bound to ctx by Do/DoTx, if any. It originally
+// returned a *sql.Tx directly; once Uow became backend-agnostic it was
+// generalized to the Tx interface, and SQLTx was added for callers that
+// specifically need the underlying *sql.Tx.
+func WithTx(ctx context.Context) (Tx, bool) {
+	h, ok := txHandleFromContext(ctx)
+	if !ok {
+		return nil, false
 	}
-	tx, err := u.Db.BeginTx(ctx, nil)
+	return h.tx, true
+}
+
+// SQLTx is a convenience for database/sql-backed Uows: it returns the
+// ctx-bound *sql.Tx directly, without going through the backend-neutral Tx
+// interface.
+func SQLTx(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := WithTx(ctx)
+	if !ok {
+		return nil, false
+	}
+	sqlTx, ok := tx.Handle().(*sql.Tx)
+	return sqlTx, ok
+}
+
+// txState tracks where a transaction (or nested savepoint scope) is in its
+// lifecycle, so that a second Commit/Rollback on an already-finalized
+// transaction is rejected instead of panicking or deadlocking.
+type txState int32
+
+const (
+	stateIdle txState = iota
+	stateActive
+	stateCommitted
+	stateRolledBack
+)
+
+// ErrTxDone is returned by CommitOrRollback or Rollback when the ctx-bound
+// transaction has already been committed or rolled back.
+var ErrTxDone = errors.New("uow: transaction has already been committed or rolled back")
+
+// Option configures a single Do/DoTx call.
+type Option func(*txConfig)
+
+// txConfig holds the per-call settings built up by the Option list passed
+// to DoTx.
+type txConfig struct {
+	txOpts        TxOptions
+	ignoredErrors []error
+}
+
+func buildTxConfig(opts []Option) *txConfig {
+	cfg := &txConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithTxOptions sets the TxOptions (isolation level, read-only) used to
+// begin the transaction. Ignored when DoTx is entered with a context that
+// already carries a transaction, since a SAVEPOINT inherits the outer
+// transaction's options.
+func WithTxOptions(txOpts TxOptions) Option {
+	return func(cfg *txConfig) { cfg.txOpts = txOpts }
+}
+
+// WithIsolation sets the transaction's isolation level.
+func WithIsolation(level IsolationLevel) Option {
+	return func(cfg *txConfig) { cfg.txOpts.Isolation = level }
+}
+
+// WithReadOnly marks the transaction as read-only.
+func WithReadOnly() Option {
+	return func(cfg *txConfig) { cfg.txOpts.ReadOnly = true }
+}
+
+// WithIgnoredErrors registers sentinel errors that should not abort the
+// transaction: when fn returns an error matching one of errs via
+// errors.Is, CommitOrRollback still commits, and the original error is
+// returned to the caller unchanged. This lets callers model domain-level
+// no-op errors (e.g. ErrAlreadyExists) without rolling back the batch.
+func WithIgnoredErrors(errs ...error) Option {
+	return func(cfg *txConfig) { cfg.ignoredErrors = append(cfg.ignoredErrors, errs...) }
+}
+
+// Do runs fn inside a transaction using the default options. It is a thin
+// wrapper around DoTx kept for backward compatibility. Per-call
+// configuration (isolation level, read-only, ignored errors) lives on DoTx's
+// Option args rather than on NewUow or Do: those options only ever apply to
+// a single transaction, so threading them through the constructor would
+// just mean overriding them at every call site that needs something other
+// than the default.
+func (u *Uow) Do(ctx context.Context, fn func(ctx context.Context, uow *Uow) error) error {
+	return u.DoTx(ctx, fn)
+}
+
+// DoTx runs fn inside a transaction configured by opts. When ctx does not
+// already carry a transaction, a fresh one is started via u.beginner. When
+// ctx already carries one (because DoTx was called from within another
+// Do/DoTx), fn instead runs inside a SAVEPOINT so that an inner failure
+// only unwinds the inner scope, leaving the outer transaction free to
+// continue. Nesting requires the backend's Tx to implement Savepointer;
+// otherwise DoTx returns ErrNestedTxUnsupported.
+func (u *Uow) DoTx(ctx context.Context, fn func(ctx context.Context, uow *Uow) error, opts ...Option) error {
+	cfg := buildTxConfig(opts)
+
+	if _, ok := WithTx(ctx); ok {
+		return u.doNested(ctx, cfg, fn)
+	}
+
+	tx, err := u.beginner.Begin(ctx, cfg.txOpts)
 	if err != nil {
 		return err
 	}
-	u.Tx = tx
-	res := fn(u)
-	if res != nil {
-		return u.Rollback(res)
+	txCtx := withConfig(withTx(ctx, tx, 0, nil), cfg)
+	res := fn(txCtx, u)
+	return u.CommitOrRollback(txCtx, res)
+}
+
+func (u *Uow) doNested(ctx context.Context, cfg *txConfig, fn func(ctx context.Context, uow *Uow) error) error {
+	h, _ := txHandleFromContext(ctx)
+	sp, ok := h.tx.(Savepointer)
+	if !ok {
+		return ErrNestedTxUnsupported
+	}
+	depth := h.depth + 1
+	name := savepointName(depth)
+	if err := sp.Savepoint(ctx, name); err != nil {
+		return err
 	}
-	return u.CommitOrRollback(res)
+	nestedCtx := withConfig(withTx(ctx, h.tx, depth, h), cfg)
+	res := fn(nestedCtx, u)
+	return u.CommitOrRollback(nestedCtx, res)
 }
 
-func (u *Uow) CommitOrRollback(res error) error {
-	err := u.Tx.Commit()
-	if err != nil {
-		if resp := u.Rollback(err); resp != nil {
-			u.mu.Unlock()
-			return fmt.Errorf("commit error: %s, rollback error: %s", err, resp.Error())
+// CommitOrRollback finalizes the ctx-bound transaction: it rolls back when
+// res is non-nil and not one of the errors registered via
+// WithIgnoredErrors, otherwise it commits (or, inside a nested scope,
+// releases the savepoint). The original res is returned unchanged on
+// success so callers can use it directly as their own return value.
+// Calling this (or Rollback) a second time on the same transaction returns
+// ErrTxDone rather than re-issuing Commit/Rollback on an already-finalized
+// Tx. If the underlying Commit itself fails, the transaction is settled as
+// rolled back (running rollback hooks and dropping commit hooks) rather
+// than left active, since Commit is not safe to retry on a Tx that may
+// have already applied part of its effect.
+func (u *Uow) CommitOrRollback(ctx context.Context, res error) error {
+	h, ok := txHandleFromContext(ctx)
+	if !ok {
+		return errors.New("no transaction bound to context")
+	}
+
+	h.mu.Lock()
+	if h.state != stateActive {
+		h.mu.Unlock()
+		return ErrTxDone
+	}
+	if res != nil && !isIgnoredError(ctx, res) {
+		return u.rollbackLocked(ctx, h, res)
+	}
+	if h.depth > 0 {
+		sp := h.tx.(Savepointer)
+		if err := sp.ReleaseSavepoint(ctx, savepointName(h.depth)); err != nil {
+			h.mu.Unlock()
+			return err
 		}
-		u.mu.Unlock()
-		return (err)
+		h.state = stateCommitted
+		return bubbleCommitHooks(h, res)
+	}
+	if err := h.tx.Commit(ctx); err != nil {
+		h.state = stateRolledBack
+		return runRolledBackHooks(ctx, h, err)
 	}
-	u.Tx = nil
-	u.mu.Unlock()
+	h.state = stateCommitted
+	return runCommittedHooks(ctx, h, res)
+}
+
+// bubbleCommitHooks hands h's queued commit hooks off to its parent scope
+// instead of running them: releasing a savepoint isn't durable on its own,
+// since the enclosing transaction can still roll back everything the nested
+// scope did, so the hooks must wait for the true root commit. h's rollback
+// hooks are dropped, matching CommitOrRollback's usual behavior on success.
+// Callers must hold h.mu on entry; it is released before bubbleCommitHooks
+// returns.
+func bubbleCommitHooks(h *txHandle, res error) error {
+	hooks := h.commitHooks
+	h.commitHooks, h.rollbackHooks = nil, nil
+	h.mu.Unlock()
+
+	parent := h.parent
+	parent.mu.Lock()
+	parent.commitHooks = append(parent.commitHooks, hooks...)
+	parent.mu.Unlock()
+
 	return res
 }
 
-func (u *Uow) Rollback(res error) error {
-	if u.Tx == nil {
-		u.mu.Unlock()
+// runCommittedHooks snapshots and clears h's queued commit hooks (dropping
+// its rollback hooks unrun), unlocks h.mu, and only then runs the commit
+// hooks now that the transaction is durably committed. Callers must hold
+// h.mu on entry; it is released before runCommittedHooks returns, so a
+// hook that calls back into OnCommit/OnRollback/ClearHooks doesn't try to
+// re-lock the same non-reentrant mutex.
+func runCommittedHooks(ctx context.Context, h *txHandle, res error) error {
+	hooks := h.commitHooks
+	h.commitHooks, h.rollbackHooks = nil, nil
+	h.mu.Unlock()
+
+	if err := runHooks(ctx, hooks); err != nil {
+		return err
+	}
+	return res
+}
+
+// Rollback aborts the ctx-bound transaction (or, inside a nested scope,
+// rolls back to its savepoint) and returns res unchanged so callers can
+// propagate the error that triggered the rollback. Calling this a second
+// time, or after CommitOrRollback already finalized the transaction,
+// returns ErrTxDone.
+func (u *Uow) Rollback(ctx context.Context, res error) error {
+	h, ok := txHandleFromContext(ctx)
+	if !ok {
 		return errors.New("no transaction to rollback")
 	}
-	err := u.Tx.Rollback()
-	if err != nil {
-		u.mu.Unlock()
+
+	h.mu.Lock()
+	if h.state != stateActive {
+		h.mu.Unlock()
+		return ErrTxDone
+	}
+	return u.rollbackLocked(ctx, h, res)
+}
+
+// rollbackLocked performs the actual rollback/ROLLBACK TO and transitions
+// h.state. Callers must hold h.mu on entry; it is released before
+// rollbackLocked returns.
+func (u *Uow) rollbackLocked(ctx context.Context, h *txHandle, res error) error {
+	if h.depth > 0 {
+		sp := h.tx.(Savepointer)
+		if err := sp.RollbackToSavepoint(ctx, savepointName(h.depth)); err != nil {
+			h.mu.Unlock()
+			return err
+		}
+		h.state = stateRolledBack
+		return runRolledBackHooks(ctx, h, res)
+	}
+	if err := h.tx.Rollback(ctx); err != nil {
+		h.mu.Unlock()
 		return err
 	}
-	u.Tx = nil
-	u.mu.Unlock()
+	h.state = stateRolledBack
+	return runRolledBackHooks(ctx, h, res)
+}
+
+// runRolledBackHooks snapshots and clears h's queued rollback hooks
+// (dropping its commit hooks unrun), unlocks h.mu, and only then runs the
+// rollback hooks now that the transaction is durably rolled back. Callers
+// must hold h.mu on entry; it is released before runRolledBackHooks
+// returns, so a hook that calls back into OnCommit/OnRollback/ClearHooks
+// doesn't try to re-lock the same non-reentrant mutex.
+func runRolledBackHooks(ctx context.Context, h *txHandle, res error) error {
+	hooks := h.rollbackHooks
+	h.commitHooks, h.rollbackHooks = nil, nil
+	h.mu.Unlock()
+
+	if hookErr := runHooks(ctx, hooks); hookErr != nil {
+		return hookErr
+	}
 	return res
 }
 
-func (u *Uow) UnRegister(name string) {
-	delete(u.Repositories, name)
+// txHandle carries the depth and lifecycle state of an active transaction
+// (or nested savepoint scope) alongside the Tx itself, so nested Do calls
+// know whether to savepoint against an existing transaction or begin a new
+// one, and so a transaction can only be finalized once. parent is nil at
+// depth 0 and points at the enclosing scope's txHandle otherwise, so a
+// nested scope's commit hooks can be bubbled up to the root.
+type txHandle struct {
+	tx            Tx
+	depth         int
+	parent        *txHandle
+	mu            sync.Mutex
+	state         txState
+	commitHooks   []CommitHook
+	rollbackHooks []RollbackHook
+}
+
+func withTx(ctx context.Context, tx Tx, depth int, parent *txHandle) context.Context {
+	return context.WithValue(ctx, txCtxKey{}, &txHandle{tx: tx, depth: depth, parent: parent, state: stateActive})
+}
+
+func txHandleFromContext(ctx context.Context) (*txHandle, bool) {
+	h, ok := ctx.Value(txCtxKey{}).(*txHandle)
+	return h, ok
+}
+
+func savepointName(depth int) string {
+	return fmt.Sprintf("sp_%d", depth)
+}
+
+// configCtxKey is the private key type used to stash the active txConfig
+// on a context.Context, alongside the transaction itself, so that
+// CommitOrRollback can consult WithIgnoredErrors without threading cfg
+// through every call site.
+type configCtxKey struct{}
+
+func withConfig(ctx context.Context, cfg *txConfig) context.Context {
+	return context.WithValue(ctx, configCtxKey{}, cfg)
+}
+
+func isIgnoredError(ctx context.Context, err error) bool {
+	cfg, ok := ctx.Value(configCtxKey{}).(*txConfig)
+	if !ok {
+		return false
+	}
+	for _, ignored := range cfg.ignoredErrors {
+		if errors.Is(err, ignored) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,316 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeDriver is a minimal database/sql driver backing just enough of the
+// connection lifecycle (BeginTx/Commit/Rollback/ExecContext) to exercise
+// Uow's finalization logic without a real database.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fakeConn: Prepare not implemented")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) { return &fakeTx{}, nil }
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return &fakeTx{}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func init() {
+	sql.Register("uow_fake", fakeDriver{})
+}
+
+// fakeFailingCommitDriver is fakeDriver except its transactions always fail
+// to commit, letting tests exercise CommitOrRollback's handling of a
+// Commit error without a real database.
+type fakeFailingCommitDriver struct{}
+
+func (fakeFailingCommitDriver) Open(name string) (driver.Conn, error) {
+	return &fakeFailingCommitConn{}, nil
+}
+
+type fakeFailingCommitConn struct{}
+
+func (c *fakeFailingCommitConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fakeFailingCommitConn: Prepare not implemented")
+}
+
+func (c *fakeFailingCommitConn) Close() error { return nil }
+
+func (c *fakeFailingCommitConn) Begin() (driver.Tx, error) { return &fakeFailingCommitTx{}, nil }
+
+func (c *fakeFailingCommitConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return &fakeFailingCommitTx{}, nil
+}
+
+func (c *fakeFailingCommitConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+
+var errFakeCommitFailed = errors.New("fakeFailingCommitTx: commit failed")
+
+type fakeFailingCommitTx struct{}
+
+func (fakeFailingCommitTx) Commit() error   { return errFakeCommitFailed }
+func (fakeFailingCommitTx) Rollback() error { return nil }
+
+func init() {
+	sql.Register("uow_fake_failing_commit", fakeFailingCommitDriver{})
+}
+
+func newTestFailingCommitUow(t *testing.T) *Uow {
+	t.Helper()
+	db, err := sql.Open("uow_fake_failing_commit", "")
+	if err != nil {
+		t.Fatalf("open fake db: %v", err)
+	}
+	u, err := NewUow(context.Background(), db)
+	if err != nil {
+		t.Fatalf("new uow: %v", err)
+	}
+	return u
+}
+
+func newTestUow(t *testing.T) *Uow {
+	t.Helper()
+	db, err := sql.Open("uow_fake", "")
+	if err != nil {
+		t.Fatalf("open fake db: %v", err)
+	}
+	u, err := NewUow(context.Background(), db)
+	if err != nil {
+		t.Fatalf("new uow: %v", err)
+	}
+	return u
+}
+
+func activeTxCtx(t *testing.T, u *Uow) context.Context {
+	t.Helper()
+	tx, err := u.beginner.Begin(context.Background(), TxOptions{})
+	if err != nil {
+		t.Fatalf("begin tx: %v", err)
+	}
+	return withTx(context.Background(), tx, 0, nil)
+}
+
+func TestCommitOrRollback_DoubleCommitReturnsErrTxDone(t *testing.T) {
+	u := newTestUow(t)
+	ctx := activeTxCtx(t, u)
+
+	if err := u.CommitOrRollback(ctx, nil); err != nil {
+		t.Fatalf("first commit: unexpected error: %v", err)
+	}
+	if err := u.CommitOrRollback(ctx, nil); !errors.Is(err, ErrTxDone) {
+		t.Fatalf("second commit: expected ErrTxDone, got %v", err)
+	}
+}
+
+func TestRollback_DoubleRollbackReturnsErrTxDone(t *testing.T) {
+	u := newTestUow(t)
+	ctx := activeTxCtx(t, u)
+	cause := errors.New("boom")
+
+	if err := u.Rollback(ctx, cause); !errors.Is(err, cause) {
+		t.Fatalf("first rollback: expected %v, got %v", cause, err)
+	}
+	if err := u.Rollback(ctx, cause); !errors.Is(err, ErrTxDone) {
+		t.Fatalf("second rollback: expected ErrTxDone, got %v", err)
+	}
+}
+
+func TestDoTx_IgnoredErrorStillCommits(t *testing.T) {
+	u := newTestUow(t)
+	errAlreadyExists := errors.New("already exists")
+
+	err := u.DoTx(context.Background(), func(ctx context.Context, uow *Uow) error {
+		return errAlreadyExists
+	}, WithIgnoredErrors(errAlreadyExists))
+
+	if !errors.Is(err, errAlreadyExists) {
+		t.Fatalf("expected ignored error to be returned, got %v", err)
+	}
+}
+
+func TestDoTx_CommitHooksRunFIFOAndRollbackHooksAreDropped(t *testing.T) {
+	u := newTestUow(t)
+	var order []string
+
+	err := u.DoTx(context.Background(), func(ctx context.Context, uow *Uow) error {
+		if err := uow.OnCommit(ctx, func(ctx context.Context) error {
+			order = append(order, "first")
+			return nil
+		}); err != nil {
+			t.Fatalf("OnCommit: %v", err)
+		}
+		if err := uow.OnCommit(ctx, func(ctx context.Context) error {
+			order = append(order, "second")
+			return nil
+		}); err != nil {
+			t.Fatalf("OnCommit: %v", err)
+		}
+		if err := uow.OnRollback(ctx, func(ctx context.Context) error {
+			order = append(order, "should not run")
+			return nil
+		}); err != nil {
+			t.Fatalf("OnRollback: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("DoTx: unexpected error: %v", err)
+	}
+	if got, want := order, []string{"first", "second"}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("hooks ran in unexpected order: %v", got)
+	}
+}
+
+func TestDoTx_RollbackHookPanicRecovered(t *testing.T) {
+	u := newTestUow(t)
+	cause := errors.New("boom")
+
+	err := u.DoTx(context.Background(), func(ctx context.Context, uow *Uow) error {
+		if err := uow.OnRollback(ctx, func(ctx context.Context) error {
+			panic("hook exploded")
+		}); err != nil {
+			t.Fatalf("OnRollback: %v", err)
+		}
+		return cause
+	})
+	if err == nil || errors.Is(err, cause) {
+		t.Fatalf("expected the recovered hook panic to surface, got %v", err)
+	}
+}
+
+func TestDoTx_HookCanCallBackIntoHookRegistrationWithoutDeadlock(t *testing.T) {
+	u := newTestUow(t)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- u.DoTx(context.Background(), func(ctx context.Context, uow *Uow) error {
+			return uow.OnCommit(ctx, func(ctx context.Context) error {
+				return uow.ClearHooks(ctx)
+			})
+		})
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("DoTx: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("DoTx deadlocked when a commit hook called back into ClearHooks")
+	}
+}
+
+func TestCommitOrRollback_AfterRollbackReturnsErrTxDone(t *testing.T) {
+	u := newTestUow(t)
+	ctx := activeTxCtx(t, u)
+
+	if err := u.Rollback(ctx, errors.New("boom")); err == nil {
+		t.Fatalf("expected rollback error to be returned")
+	}
+	if err := u.CommitOrRollback(ctx, nil); !errors.Is(err, ErrTxDone) {
+		t.Fatalf("commit after rollback: expected ErrTxDone, got %v", err)
+	}
+}
+
+func TestDoTx_NestedCommitHookRunsOnRootCommit(t *testing.T) {
+	u := newTestUow(t)
+	ran := false
+
+	err := u.DoTx(context.Background(), func(ctx context.Context, uow *Uow) error {
+		return uow.DoTx(ctx, func(ctx context.Context, uow *Uow) error {
+			return uow.OnCommit(ctx, func(ctx context.Context) error {
+				ran = true
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		t.Fatalf("DoTx: unexpected error: %v", err)
+	}
+	if !ran {
+		t.Fatalf("expected nested commit hook to run once the root transaction committed")
+	}
+}
+
+func TestDoTx_NestedCommitHookDroppedOnOuterRollback(t *testing.T) {
+	u := newTestUow(t)
+	cause := errors.New("boom")
+	ran := false
+
+	err := u.DoTx(context.Background(), func(ctx context.Context, uow *Uow) error {
+		if err := uow.DoTx(ctx, func(ctx context.Context, uow *Uow) error {
+			return uow.OnCommit(ctx, func(ctx context.Context) error {
+				ran = true
+				return nil
+			})
+		}); err != nil {
+			return err
+		}
+		return cause
+	})
+	if !errors.Is(err, cause) {
+		t.Fatalf("expected outer error to be returned, got %v", err)
+	}
+	if ran {
+		t.Fatalf("expected nested commit hook to be dropped when the outer transaction rolled back")
+	}
+}
+
+func TestCommitOrRollback_FailedCommitSettlesAsRolledBack(t *testing.T) {
+	u := newTestFailingCommitUow(t)
+	ctx := activeTxCtx(t, u)
+	rollbackHookRan := false
+	if err := u.OnRollback(ctx, func(ctx context.Context) error {
+		rollbackHookRan = true
+		return nil
+	}); err != nil {
+		t.Fatalf("OnRollback: %v", err)
+	}
+	if err := u.OnCommit(ctx, func(ctx context.Context) error {
+		t.Fatalf("commit hook must not run when Commit itself fails")
+		return nil
+	}); err != nil {
+		t.Fatalf("OnCommit: %v", err)
+	}
+
+	if err := u.CommitOrRollback(ctx, nil); !errors.Is(err, errFakeCommitFailed) {
+		t.Fatalf("expected the commit error to be returned, got %v", err)
+	}
+	if !rollbackHookRan {
+		t.Fatalf("expected rollback hooks to run once Commit failed")
+	}
+	if err := u.CommitOrRollback(ctx, nil); !errors.Is(err, ErrTxDone) {
+		t.Fatalf("commit after failed commit: expected ErrTxDone, got %v", err)
+	}
+	if err := u.Rollback(ctx, errors.New("boom")); !errors.Is(err, ErrTxDone) {
+		t.Fatalf("rollback after failed commit: expected ErrTxDone, got %v", err)
+	}
+}